@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	adminToken    = flag.String("admin.token", "", "shared-secret required in the X-Admin-Token header to call the /plugins/{key}/* action endpoints (-enable-actions)")
+	enableActions = flag.Bool("enable-actions", false, "opt-in to expose the /plugins/{key}/(enable|disable|upgrade) and DELETE /plugins/{key} action endpoints")
+
+	pluginActionTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "plugin_action_total",
+			Help:      "Counts plugin lifecycle actions taken via the /plugins/{key}/* action endpoints",
+		},
+		[]string{
+			"action",
+			"key",
+			"result",
+		},
+	)
+)
+
+// constantTimeEquals reports whether a and b are equal without leaking timing information about
+// how many leading characters match, so an attacker probing X-Admin-Token can't narrow down the
+// shared secret one byte at a time.
+func constantTimeEquals(a, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// pluginActionHandler implements the authenticated action subsystem used to drive UPM plugin
+// state from the exporter: POST /plugins/{key}/enable, POST /plugins/{key}/disable,
+// DELETE /plugins/{key}, and POST /plugins/{key}/upgrade. It requires -enable-actions and a
+// matching -admin.token shared-secret so read-only deployments are unaffected. target selects
+// which Atlassian application to act on the same way /probe does: if given it's resolved
+// against -config.file, otherwise the single-target -app.fqdn/-app.token/-app.protocol flags
+// are used.
+func pluginActionHandler(w http.ResponseWriter, r *http.Request) {
+	if *adminToken == "" || !constantTimeEquals(r.Header.Get("X-Admin-Token"), *adminToken) {
+		http.Error(w, "missing or invalid X-Admin-Token header", http.StatusUnauthorized)
+		return
+	}
+
+	key, action, ok := parsePluginActionPath(r.Method, r.URL.Path)
+	if !ok {
+		http.Error(w, "unsupported method/path for plugin action", http.StatusMethodNotAllowed)
+		return
+	}
+
+	target := r.URL.Query().Get("target")
+	targetFqdn, targetToken, targetProtocol, err := resolveActionTarget(target)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	baseURL := targetProtocol + "://" + targetFqdn + "/rest/plugins/latest/"
+	bearer := "Basic " + targetToken
+
+	log.Debug("plugin action requested: ", action, " key: ", key, " target: ", targetFqdn)
+
+	err = performPluginAction(baseURL, bearer, key, action)
+
+	result := "success"
+	if err != nil {
+		result = "error"
+		log.Error("plugin action failed: ", err)
+	}
+	pluginActionTotal.WithLabelValues(action, key, result).Inc()
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	fmt.Fprintf(w, "%s %s: ok\n", action, key)
+}
+
+// parsePluginActionPath extracts the plugin key and action from an incoming
+// /plugins/{key}/{action} request, returning ok=false if method/path don't match a
+// supported action.
+func parsePluginActionPath(method, path string) (key, action string, ok bool) {
+	path = strings.TrimPrefix(path, "/plugins/")
+	path = strings.Trim(path, "/")
+
+	switch {
+	case method == http.MethodDelete:
+		return path, "uninstall", path != ""
+	case method == http.MethodPost && strings.HasSuffix(path, "/enable"):
+		return strings.TrimSuffix(path, "/enable"), "enable", true
+	case method == http.MethodPost && strings.HasSuffix(path, "/disable"):
+		return strings.TrimSuffix(path, "/disable"), "disable", true
+	case method == http.MethodPost && strings.HasSuffix(path, "/upgrade"):
+		return strings.TrimSuffix(path, "/upgrade"), "upgrade", true
+	default:
+		return "", "", false
+	}
+}
+
+// resolveActionTarget resolves the FQDN/token/protocol to act against. If target is empty the
+// single-target -app.fqdn/-app.token/-app.protocol flags are used, otherwise target is looked
+// up in the -config.file targets loaded for /probe.
+func resolveActionTarget(target string) (fqdnOut, tokenOut, protocolOut string, err error) {
+	if target == "" {
+		if *fqdn == "" || *token == "" {
+			return "", "", "", fmt.Errorf("no target parameter given and -app.fqdn/-app.token are not set")
+		}
+		return *fqdn, *token, *protocol, nil
+	}
+
+	cfg, ok := targetsCfg.Targets[target]
+	if !ok {
+		return "", "", "", fmt.Errorf("no -config.file entry found for target: %s", target)
+	}
+
+	proto := cfg.Protocol
+	if proto == "" {
+		proto = "https"
+	}
+
+	return target, cfg.Token, proto, nil
+}
+
+// performPluginAction translates action into the corresponding UPM REST call for the plugin
+// identified by key.
+func performPluginAction(baseURL, bearer, key, action string) error {
+	switch action {
+	case "enable":
+		return setPluginEnabled(baseURL, bearer, key, true)
+	case "disable":
+		return setPluginEnabled(baseURL, bearer, key, false)
+	case "uninstall":
+		return uninstallPlugin(baseURL, bearer, key)
+	case "upgrade":
+		return upgradePlugin(baseURL, bearer, key)
+	default:
+		return fmt.Errorf("unsupported plugin action: %s", action)
+	}
+}
+
+// setPluginEnabled PUTs the enabled state for key, the same request the UPM web console makes
+// when an admin toggles a plugin's enabled switch.
+func setPluginEnabled(baseURL, bearer, key string, enabled bool) error {
+	body := fmt.Sprintf(`{"enabled":%t}`, enabled)
+
+	req, err := http.NewRequest("PUT", baseURL+key+"-key", bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Authorization", bearer)
+	req.Header.Add("content-type", "application/vnd.atl.plugins.plugin+json")
+
+	return doActionRequest(req)
+}
+
+// uninstallPlugin DELETEs the plugin identified by key from the UPM.
+func uninstallPlugin(baseURL, bearer, key string) error {
+	req, err := http.NewRequest("DELETE", baseURL+key+"-key", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Authorization", bearer)
+
+	return doActionRequest(req)
+}
+
+// upgradePlugin drives a plugin upgrade through UPM's two-step flow: first a GET to obtain a
+// one-time upm-token, then a POST carrying that token that triggers the upgrade to the latest
+// available version.
+func upgradePlugin(baseURL, bearer, key string) error {
+	tokenReq, err := http.NewRequest("GET", baseURL+"?os_authType=basic", nil)
+	if err != nil {
+		return err
+	}
+	tokenReq.Header.Add("Authorization", bearer)
+
+	tokenRes, err := sharedHTTPClient.Do(tokenReq)
+	if err != nil {
+		return err
+	}
+	defer tokenRes.Body.Close()
+
+	upmToken := tokenRes.Header.Get("upm-token")
+	if upmToken == "" {
+		return fmt.Errorf("did not receive an upm-token from %s", baseURL)
+	}
+
+	upgradeURL := baseURL + key + "-key?" + url.Values{"token": {upmToken}}.Encode()
+	req, err := http.NewRequest("POST", upgradeURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Authorization", bearer)
+	req.Header.Add("content-type", "application/vnd.atl.plugins.install.uri+json")
+
+	return doActionRequest(req)
+}
+
+// doActionRequest executes req against the UPM and treats any non-2xx response as an error.
+func doActionRequest(req *http.Request) error {
+	res, err := sharedHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("UPM returned status %d: %s", res.StatusCode, string(body))
+	}
+
+	return nil
+}
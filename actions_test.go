@@ -0,0 +1,157 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParsePluginActionPath(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantKey    string
+		wantAction string
+		wantOK     bool
+	}{
+		{
+			name:       "enable",
+			method:     http.MethodPost,
+			path:       "/plugins/my-plugin/enable",
+			wantKey:    "my-plugin",
+			wantAction: "enable",
+			wantOK:     true,
+		},
+		{
+			name:       "disable",
+			method:     http.MethodPost,
+			path:       "/plugins/my-plugin/disable",
+			wantKey:    "my-plugin",
+			wantAction: "disable",
+			wantOK:     true,
+		},
+		{
+			name:       "upgrade",
+			method:     http.MethodPost,
+			path:       "/plugins/my-plugin/upgrade",
+			wantKey:    "my-plugin",
+			wantAction: "upgrade",
+			wantOK:     true,
+		},
+		{
+			name:       "uninstall via DELETE",
+			method:     http.MethodDelete,
+			path:       "/plugins/my-plugin",
+			wantKey:    "my-plugin",
+			wantAction: "uninstall",
+			wantOK:     true,
+		},
+		{
+			name:   "DELETE with no key is rejected",
+			method: http.MethodDelete,
+			path:   "/plugins/",
+			wantOK: false,
+		},
+		{
+			name:   "unsupported method",
+			method: http.MethodGet,
+			path:   "/plugins/my-plugin/enable",
+			wantOK: false,
+		},
+		{
+			name:   "unsupported suffix",
+			method: http.MethodPost,
+			path:   "/plugins/my-plugin/restart",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, action, ok := parsePluginActionPath(tt.method, tt.path)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !tt.wantOK {
+				return
+			}
+			if key != tt.wantKey {
+				t.Errorf("key = %q, want %q", key, tt.wantKey)
+			}
+			if action != tt.wantAction {
+				t.Errorf("action = %q, want %q", action, tt.wantAction)
+			}
+		})
+	}
+}
+
+func TestResolveActionTarget(t *testing.T) {
+	origFqdn, origToken, origProtocol, origTargetsCfg := *fqdn, *token, *protocol, targetsCfg
+	defer func() {
+		*fqdn, *token, *protocol = origFqdn, origToken, origProtocol
+		targetsCfg = origTargetsCfg
+	}()
+
+	t.Run("empty target falls back to single-target flags", func(t *testing.T) {
+		*fqdn, *token, *protocol = "single.example.com", "single-token", "https"
+		targetsCfg = targetsConfig{}
+
+		gotFqdn, gotToken, gotProtocol, err := resolveActionTarget("")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if gotFqdn != "single.example.com" || gotToken != "single-token" || gotProtocol != "https" {
+			t.Errorf("got (%q, %q, %q), want (single.example.com, single-token, https)", gotFqdn, gotToken, gotProtocol)
+		}
+	})
+
+	t.Run("empty target errors when single-target flags are unset", func(t *testing.T) {
+		*fqdn, *token, *protocol = "", "", "https"
+		targetsCfg = targetsConfig{}
+
+		if _, _, _, err := resolveActionTarget(""); err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+	})
+
+	t.Run("named target is resolved from -config.file", func(t *testing.T) {
+		*fqdn, *token, *protocol = "", "", "https"
+		targetsCfg = targetsConfig{
+			Targets: map[string]targetConfig{
+				"fleet.example.com": {Token: "fleet-token", Protocol: "http"},
+			},
+		}
+
+		gotFqdn, gotToken, gotProtocol, err := resolveActionTarget("fleet.example.com")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if gotFqdn != "fleet.example.com" || gotToken != "fleet-token" || gotProtocol != "http" {
+			t.Errorf("got (%q, %q, %q), want (fleet.example.com, fleet-token, http)", gotFqdn, gotToken, gotProtocol)
+		}
+	})
+
+	t.Run("named target defaults to https when unset", func(t *testing.T) {
+		targetsCfg = targetsConfig{
+			Targets: map[string]targetConfig{
+				"fleet.example.com": {Token: "fleet-token"},
+			},
+		}
+
+		_, _, gotProtocol, err := resolveActionTarget("fleet.example.com")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if gotProtocol != "https" {
+			t.Errorf("protocol = %q, want https", gotProtocol)
+		}
+	})
+
+	t.Run("unknown named target errors", func(t *testing.T) {
+		targetsCfg = targetsConfig{}
+
+		if _, _, _, err := resolveActionTarget("missing.example.com"); err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+	})
+}
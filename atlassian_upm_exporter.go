@@ -19,25 +19,40 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// sharedHTTPClient is reused for every outbound request (the main scrape plus every
+// plugin-availability check) so keep-alives and idle connections are shared across the
+// worker pool in getAvailablePluginInfo instead of each request paying a fresh TCP/TLS handshake.
+var sharedHTTPClient = &http.Client{
+	Timeout: 30 * time.Second,
+	Transport: &http.Transport{
+		MaxIdleConnsPerHost: 20,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
 var (
-	baseURL         string
-	bearer          string
 	disableCol      = true
 	exporterName    = "Atlassian UPM Exporter"
 	metricNamespace = "atlassian_upm"
 
-	address          = flag.String("svc.ip-address", "0.0.0.0", "assign an IP address for the service to listen on")
-	checkUpdates     = flag.Bool("check-updates", false, "check for updates available for each plugin. (1 connection per plugin)")
-	debug            = flag.Bool("debug", false, "enable the service debug output")
-	dropJiraSoftware = flag.Bool("drop-jira-software-plugins", false, "remove plugins vendored by Atlassian when monitoring Jira")
-	dropDisabled     = flag.Bool("drop-disabled", false, "remove plugins if they are disabled")
-	enableColLogs    = flag.Bool("enable-color-logs", false, "when developing in debug mode, prettier to set this for visual colors")
-	fqdn             = flag.String("app.fqdn", "", "REQUIRED: provide the application fqdn to be monitored (ie. bitbucket.domain.com)")
-	help             = flag.Bool("help", false, "help will display this helpful dialog output")
-	port             = flag.String("svc.port", "9996", "can pass in the port to listen on.")
-	protocol         = flag.String("app.protocol", "https", "set the protocol used to interact with the application")
-	token            = flag.String("app.token", "", "REQUIRED: provide a Basic access token to connect with")
-	userInstalled    = flag.Bool("user-installed", false, "if you would like 'user-installed' plugins only")
+	address                 = flag.String("svc.ip-address", "0.0.0.0", "assign an IP address for the service to listen on")
+	checkUpdates            = flag.Bool("check-updates", false, "check for updates available for each plugin. (1 connection per plugin)")
+	checkUpdatesConcurrency = flag.Int("check-updates-concurrency", 8, "number of plugin availability checks to run concurrently when -check-updates is set")
+	configFile              = flag.String("config.file", "", "path to a YAML file mapping target FQDNs to credentials/flags, enables the /probe endpoint for multi-target scraping")
+	debug                   = flag.Bool("debug", false, "enable the service debug output")
+	dropJiraSoftware        = flag.Bool("drop-jira-software-plugins", false, "remove plugins vendored by Atlassian when monitoring Jira")
+	dropDisabled            = flag.Bool("drop-disabled", false, "remove plugins if they are disabled")
+	enableColLogs           = flag.Bool("enable-color-logs", false, "when developing in debug mode, prettier to set this for visual colors")
+	fqdn                    = flag.String("app.fqdn", "", "REQUIRED: provide the application fqdn to be monitored (ie. bitbucket.domain.com)")
+	help                    = flag.Bool("help", false, "help will display this helpful dialog output")
+	port                    = flag.String("svc.port", "9996", "can pass in the port to listen on.")
+	protocol                = flag.String("app.protocol", "https", "set the protocol used to interact with the application")
+	token                   = flag.String("app.token", "", "REQUIRED: provide a Basic access token to connect with")
+	userInstalled           = flag.Bool("user-installed", false, "if you would like 'user-installed' plugins only")
+
+	// targetsCfg holds the per-FQDN credentials/flags loaded from -config.file, used by the
+	// /probe handler to scrape an entire fleet of Atlassian applications from one exporter.
+	targetsCfg targetsConfig
 
 	usageMessage = "The Atlassian UPM (Universal Plugin Manager) Exporter is used to get the list\n" +
 		"of plugins installed on the monitored system. Currently Bitbucket, Confluence\n" +
@@ -45,6 +60,9 @@ var (
 		"with this exporter, create one per application. The account that this container\n" +
 		"will use to reach out and scrape will need to be a product Administrator to\n" +
 		"that monitored Atlassian application.\n" +
+		"\nA single exporter can also cover a whole fleet: pass -config.file with a YAML\n" +
+		"document mapping each target FQDN to its token/protocol/flags, then point\n" +
+		"Prometheus at /probe?target=<fqdn> (blackbox_exporter style) instead of /metrics.\n" +
 		"\nMetrics Example:\n" +
 		"atlassian_upm_collect_duration_seconds{url=''} 0\n" +
 		"atlassian_upm_plugin{enabled='',installedVersion='',name='',url='',userInstalled=''} 0\n" +
@@ -64,16 +82,58 @@ var usage = func() {
 }
 
 // atlassianUPMCollector is the structure of our prometheus collector containing it descriptors.
+// It also carries the per-target connection details and scrape flags, so one exporter process
+// can build a fresh collector per-request (see probeHandler) as well as keep a single long-lived
+// collector for the default /metrics endpoint.
 type atlassianUPMCollector struct {
-	atlassianUPMTimeMetric     *prometheus.Desc
-	atlassianUPMUpMetric       *prometheus.Desc
-	atlassianUPMPlugins        *prometheus.Desc
-	atlassianUPMVersionsMetric *prometheus.Desc
+	fqdn             string
+	token            string
+	baseURL          string
+	userInstalled    bool
+	dropDisabled     bool
+	dropJiraSoftware bool
+	checkUpdates     bool
+	checkLicenses    bool
+
+	// statuses tracks the last observed state per plugin key so transitions can be detected
+	// between scrapes of this collector instance.
+	statuses *statusStore
+
+	atlassianUPMTimeMetric           *prometheus.Desc
+	atlassianUPMUpMetric             *prometheus.Desc
+	atlassianUPMPlugins              *prometheus.Desc
+	atlassianUPMVersionsMetric       *prometheus.Desc
+	atlassianUPMAvailableCheckErrors *prometheus.CounterVec
+	atlassianUPMPluginStatus         *prometheus.Desc
+	atlassianUPMStateTransitions     *prometheus.CounterVec
+	atlassianUPMPendingTasks         *prometheus.Desc
+	atlassianUPMPluginInfo           *prometheus.Desc
+	atlassianUPMLicenseValid         *prometheus.Desc
+	atlassianUPMLicenseExpiry        *prometheus.Desc
+	atlassianUPMLicenseMaintExpiry   *prometheus.Desc
+	atlassianUPMLicenseCheckErrors   *prometheus.CounterVec
 }
 
 // newAtlassianUPMCollector is the constructor for our collector used to initialize the metrics.
-func newAtlassianUPMCollector() *atlassianUPMCollector {
+// fqdn, token and protocol identify the Atlassian application to scrape; the remaining
+// arguments mirror the -user-installed/-drop-disabled/-drop-jira-software-plugins/-check-updates
+// flags, but scoped to this one collector instance so they can vary per target.
+func newAtlassianUPMCollector(fqdn, token, protocol string, userInstalled, dropDisabled, dropJiraSoftware, checkUpdates, checkLicenses bool) *atlassianUPMCollector {
+	// state is keyed by fqdn and persists across collector instances, so a fresh
+	// *atlassianUPMCollector built per /probe request still accumulates status history and
+	// _total counters correctly for that target.
+	state := targetStateFor(fqdn)
+
 	return &atlassianUPMCollector{
+		fqdn:             fqdn,
+		token:            token,
+		baseURL:          protocol + "://" + fqdn + "/rest/plugins/latest/",
+		userInstalled:    userInstalled,
+		dropDisabled:     dropDisabled,
+		dropJiraSoftware: dropJiraSoftware,
+		checkUpdates:     checkUpdates,
+		checkLicenses:    checkLicenses,
+		statuses:         state.statuses,
 		atlassianUPMTimeMetric: prometheus.NewDesc(
 			metricNamespace+"_collect_duration_seconds",
 			"Used to keep track of how long the Atlassian Universal Plugin Manager (UPM) took to Collect",
@@ -117,6 +177,63 @@ func newAtlassianUPMCollector() *atlassianUPMCollector {
 			},
 			nil,
 		),
+		atlassianUPMAvailableCheckErrors: state.availableCheckErrors,
+		atlassianUPMPluginStatus: prometheus.NewDesc(
+			metricNamespace+"_plugin_status",
+			"Current lifecycle state of a plugin, one series per {key,state} pair, value is 1 for the current state",
+			[]string{
+				"key",
+				"state",
+			},
+			nil,
+		),
+		atlassianUPMStateTransitions: state.stateTransitions,
+		atlassianUPMPendingTasks: prometheus.NewDesc(
+			metricNamespace+"_pending_tasks",
+			"A plugin install/upgrade task that is pending and awaiting a restart, value is 1",
+			[]string{
+				"type",
+				"key",
+			},
+			nil,
+		),
+		atlassianUPMPluginInfo: prometheus.NewDesc(
+			metricNamespace+"_plugin_info",
+			"Marketplace/vendor information for a plugin, value is 1",
+			[]string{
+				"key",
+				"vendor",
+				"vendor_link",
+				"marketplace_link",
+				"description",
+			},
+			nil,
+		),
+		atlassianUPMLicenseValid: prometheus.NewDesc(
+			metricNamespace+"_plugin_license_valid",
+			"Whether a licensed plugin's current license is valid, value is true if valid",
+			[]string{
+				"key",
+			},
+			nil,
+		),
+		atlassianUPMLicenseExpiry: prometheus.NewDesc(
+			metricNamespace+"_plugin_license_expiry_timestamp_seconds",
+			"Unix timestamp at which a licensed plugin's license expires",
+			[]string{
+				"key",
+			},
+			nil,
+		),
+		atlassianUPMLicenseMaintExpiry: prometheus.NewDesc(
+			metricNamespace+"_plugin_license_maintenance_expiry_timestamp_seconds",
+			"Unix timestamp at which a licensed plugin's maintenance/support period expires",
+			[]string{
+				"key",
+			},
+			nil,
+		),
+		atlassianUPMLicenseCheckErrors: state.licenseCheckErrors,
 	}
 }
 
@@ -126,6 +243,15 @@ func (collector *atlassianUPMCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- collector.atlassianUPMUpMetric
 	ch <- collector.atlassianUPMPlugins
 	ch <- collector.atlassianUPMVersionsMetric
+	ch <- collector.atlassianUPMPluginStatus
+	ch <- collector.atlassianUPMPendingTasks
+	ch <- collector.atlassianUPMPluginInfo
+	ch <- collector.atlassianUPMLicenseValid
+	ch <- collector.atlassianUPMLicenseExpiry
+	ch <- collector.atlassianUPMLicenseMaintExpiry
+	collector.atlassianUPMAvailableCheckErrors.Describe(ch)
+	collector.atlassianUPMStateTransitions.Describe(ch)
+	collector.atlassianUPMLicenseCheckErrors.Describe(ch)
 }
 
 // Collect implements required collect function for all prometheus collectors
@@ -133,14 +259,19 @@ func (collector *atlassianUPMCollector) Collect(ch chan<- prometheus.Metric) {
 	startTime := time.Now()
 	log.Debug("Collect start")
 
+	// ctx is scoped to this one scrape so a slow/hung plugin-availability check can't outlive
+	// it; it's cancelled as soon as Collect returns.
+	ctx, cancel := context.WithTimeout(context.Background(), sharedHTTPClient.Timeout)
+	defer cancel()
+
 	log.Debug("create request object")
-	req, err := http.NewRequest("GET", baseURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", collector.baseURL, nil)
 	if err != nil {
-		log.Error("http.NewRequest returned an error:", err)
+		log.Error("http.NewRequestWithContext returned an error:", err)
 	}
 
 	log.Debug("create Basic auth string from argument passed")
-	bearer = "Basic " + *token
+	bearer := "Basic " + collector.token
 
 	log.Debug("add authorization header to the request")
 	req.Header.Add("Authorization", bearer)
@@ -149,10 +280,10 @@ func (collector *atlassianUPMCollector) Collect(ch chan<- prometheus.Metric) {
 	req.Header.Add("content-type", "application/json")
 
 	log.Debug("make request... get back a response")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := sharedHTTPClient.Do(req)
 	if err != nil {
 		log.Debug("set metric atlassian_upm_rest_url_up")
-		ch <- prometheus.MustNewConstMetric(collector.atlassianUPMUpMetric, prometheus.GaugeValue, 0, *fqdn)
+		ch <- prometheus.MustNewConstMetric(collector.atlassianUPMUpMetric, prometheus.GaugeValue, 0, collector.fqdn)
 		log.Warn("http.DefaultClient.Do returned an error:", err, " return from Collect")
 		return
 	}
@@ -163,7 +294,7 @@ func (collector *atlassianUPMCollector) Collect(ch chan<- prometheus.Metric) {
 	}
 
 	log.Debug("set metric atlassian_upm_rest_url_up")
-	ch <- prometheus.MustNewConstMetric(collector.atlassianUPMUpMetric, prometheus.GaugeValue, 1, *fqdn)
+	ch <- prometheus.MustNewConstMetric(collector.atlassianUPMUpMetric, prometheus.GaugeValue, 1, collector.fqdn)
 
 	var allPlugins restPlugins
 	if resp.StatusCode == 200 {
@@ -171,20 +302,20 @@ func (collector *atlassianUPMCollector) Collect(ch chan<- prometheus.Metric) {
 		allPlugins = plugins(resp)
 
 		// return user-installed plugins if argument passed
-		if *userInstalled {
+		if collector.userInstalled {
 			log.Debug("-user-installed found")
 			allPlugins = userInstalledPlugins(allPlugins)
 		}
 
 		// plugins have the ability to be installed, but disabled, this will remove them if disabled
-		if *dropDisabled {
+		if collector.dropDisabled {
 			log.Debug("-drop-disabled found")
 			allPlugins = dropDisabledPlugins(allPlugins)
 		}
 
 		// Jira specific
 		// some plugins maintained by Jira have an additional element, this gives the option to drop those plugins
-		if *dropJiraSoftware {
+		if collector.dropJiraSoftware {
 			log.Debug("-drop-jira-software found")
 			allPlugins = dropJiraSoftwarePlugins(allPlugins)
 		}
@@ -202,14 +333,72 @@ func (collector *atlassianUPMCollector) Collect(ch chan<- prometheus.Metric) {
 				string(plugin.Key),
 				string(plugin.Version),
 				strconv.FormatBool(plugin.UserInstalled),
-				*fqdn,
+				collector.fqdn,
+			)
+
+			ch <- prometheus.MustNewConstMetric(
+				collector.atlassianUPMPluginInfo,
+				prometheus.GaugeValue,
+				1,
+				plugin.Key,
+				plugin.Vendor.Name,
+				plugin.Vendor.Link,
+				plugin.Vendor.MarketplaceLink,
+				plugin.Description,
 			)
+
+			if collector.checkLicenses && plugin.UsesLicensing {
+				log.Debug("checking license for plugin: ", plugin.Key)
+				license, err := fetchPluginLicense(ctx, collector.baseURL, bearer, plugin.Key)
+				if err != nil {
+					log.Warn("fetchPluginLicense returned an error for plugin: ", plugin.Key, ", error: ", err)
+					collector.atlassianUPMLicenseCheckErrors.WithLabelValues(plugin.Key).Inc()
+					continue
+				}
+
+				ch <- prometheus.MustNewConstMetric(collector.atlassianUPMLicenseValid, prometheus.GaugeValue, boolToFloat(license.Valid), plugin.Key)
+				ch <- prometheus.MustNewConstMetric(collector.atlassianUPMLicenseExpiry, prometheus.GaugeValue, float64(license.ExpiryDate)/1000, plugin.Key)
+				ch <- prometheus.MustNewConstMetric(collector.atlassianUPMLicenseMaintExpiry, prometheus.GaugeValue, float64(license.MaintenanceExpiryDate)/1000, plugin.Key)
+			}
+		}
+
+		log.Debug("poll pending plugin tasks")
+		pendingByKey, err := fetchPendingTasks(ctx, collector.baseURL, bearer)
+		if err != nil {
+			log.Warn("fetchPendingTasks returned an error:", err)
+			pendingByKey = map[string]string{}
+		}
+
+		now := time.Now()
+		for _, plugin := range allPlugins.Plugins {
+			pendingType, hadPending := pendingByKey[plugin.Key]
+			status := pluginStatusFor(plugin, pendingType, hadPending)
+
+			previous, hadPrevious := collector.statuses.observe(plugin.Key, status, now)
+			if hadPrevious && previous != status {
+				log.Debug("plugin: ", plugin.Key, " transitioned from: ", previous, " to: ", status)
+				collector.atlassianUPMStateTransitions.WithLabelValues(plugin.Key, string(previous), string(status)).Inc()
+			}
+
+			for _, candidate := range allPluginStatuses {
+				ch <- prometheus.MustNewConstMetric(
+					collector.atlassianUPMPluginStatus,
+					prometheus.GaugeValue,
+					boolToFloat(candidate == status),
+					plugin.Key,
+					string(candidate),
+				)
+			}
+		}
+
+		for key, taskType := range pendingByKey {
+			ch <- prometheus.MustNewConstMetric(collector.atlassianUPMPendingTasks, prometheus.GaugeValue, 1, taskType, key)
 		}
 	}
 
-	if resp.StatusCode == 200 && *checkUpdates {
+	if resp.StatusCode == 200 && collector.checkUpdates {
 		log.Debug("get remaining plugins available info")
-		availablePluginsMap := getAvailablePluginInfo(allPlugins)
+		availablePluginsMap := getAvailablePluginInfo(ctx, allPlugins, collector.baseURL, bearer, collector.atlassianUPMAvailableCheckErrors)
 
 		log.Debug("range over values in response, add each as metric with labels")
 		for _, plugin := range availablePluginsMap {
@@ -231,49 +420,57 @@ func (collector *atlassianUPMCollector) Collect(ch chan<- prometheus.Metric) {
 				string(plugin.InstalledVersion),
 				strconv.FormatBool(plugin.Enabled), // convert bool to string for the 'enabled' value in the labels
 				strconv.FormatBool(plugin.UserInstalled),
-				*fqdn,
+				collector.fqdn,
 			)
 		}
 	}
 
+	collector.atlassianUPMAvailableCheckErrors.Collect(ch)
+	collector.atlassianUPMStateTransitions.Collect(ch)
+	collector.atlassianUPMLicenseCheckErrors.Collect(ch)
+
 	finishTime := time.Now()
 	elapsedTime := finishTime.Sub(startTime)
 	log.Debug("set the duration metric")
-	ch <- prometheus.MustNewConstMetric(collector.atlassianUPMTimeMetric, prometheus.GaugeValue, elapsedTime.Seconds(), *fqdn)
+	ch <- prometheus.MustNewConstMetric(collector.atlassianUPMTimeMetric, prometheus.GaugeValue, elapsedTime.Seconds(), collector.fqdn)
 
 	log.Debug("Collect finished")
 }
 
-// restPlugins structure associated with the rest/plugins/1.0/ endpoint.
+// restPlugin is a single entry of the rest/plugins/1.0/ response.
 // Have dropped most of the response, can check with: curl -s -u peter.gallerani@polarisalpha.com:$PA_PW https://bitbucket.polarisalpha.com/rest/plugins/latest/ | jq '.'"plugins"[0]
+type restPlugin struct {
+	Enabled bool `json:"enabled"`
+	Links   struct {
+		Self          string `json:"self"`
+		PluginSummary string `json:"plugin-summary"`
+		Modify        string `json:"modify"`
+		PluginIcon    string `json:"plugin-icon"`
+		PluginLogo    string `json:"plugin-logo"`
+		Manage        string `json:"manage"`
+	} `json:"links"`
+	Name           string `json:"name"`
+	Version        string `json:"version"`
+	UserInstalled  bool   `json:"userInstalled"`
+	Optional       bool   `json:"optional"`
+	Static         bool   `json:"static"`
+	Unloadable     bool   `json:"unloadable"`
+	UnloadableText string `json:"unloadableText,omitempty"` // set when Unloadable because the plugin errored on startup, vs. simply being inapplicable
+	Description    string `json:"description"`
+	Key            string `json:"key"`
+	UsesLicensing  bool   `json:"usesLicensing"`
+	Remotable      bool   `json:"remotable"`
+	Vendor         struct {
+		Name            string `json:"name"`
+		MarketplaceLink string `json:"marketplaceLink"`
+		Link            string `json:"link"`
+	} `json:"vendor"`
+	ApplicationKey string `json:"applicationKey,omitempty"` // only found on some jira plugins with this key being "jira-software"
+}
+
+// restPlugins structure associated with the rest/plugins/1.0/ endpoint.
 type restPlugins struct {
-	Plugins []struct {
-		Enabled bool `json:"enabled"`
-		Links   struct {
-			Self          string `json:"self"`
-			PluginSummary string `json:"plugin-summary"`
-			Modify        string `json:"modify"`
-			PluginIcon    string `json:"plugin-icon"`
-			PluginLogo    string `json:"plugin-logo"`
-			Manage        string `json:"manage"`
-		} `json:"links"`
-		Name          string `json:"name"`
-		Version       string `json:"version"`
-		UserInstalled bool   `json:"userInstalled"`
-		Optional      bool   `json:"optional"`
-		Static        bool   `json:"static"`
-		Unloadable    bool   `json:"unloadable"`
-		Description   string `json:"description"`
-		Key           string `json:"key"`
-		UsesLicensing bool   `json:"usesLicensing"`
-		Remotable     bool   `json:"remotable"`
-		Vendor        struct {
-			Name            string `json:"name"`
-			MarketplaceLink string `json:"marketplaceLink"`
-			Link            string `json:"link"`
-		} `json:"vendor"`
-		ApplicationKey string `json:"applicationKey,omitempty"` // only found on some jira plugins with this key being "jira-software"
-	} `json:"plugins"`
+	Plugins []restPlugin `json:"plugins"`
 }
 
 // restPluginsAvailable is associated with the UPM /rest/plugins/1.0/available/<key>-key JSON structure returned.
@@ -373,66 +570,6 @@ func dropJiraSoftwarePlugins(plugins restPlugins) restPlugins {
 	return tempMap
 }
 
-// getAvailablePluginInfo uses the given map of plugins and gets the available information for that plugin.
-// The map returned is an available structure.
-func getAvailablePluginInfo(restPluginsMap restPlugins) []restPluginsAvailable {
-	var availablePluginsMap []restPluginsAvailable
-	for _, plugin := range restPluginsMap.Plugins {
-		log.Debug("getting: ", plugin.Name, ", available info")
-		availablePluginURL := baseURL + "available/" + plugin.Key + "-key"
-		log.Debug("requesting URL: " + availablePluginURL)
-		req, err := http.NewRequest("GET", availablePluginURL, nil)
-		if err != nil {
-			log.Error("http.NewRequest returned an error:", err)
-		}
-
-		log.Debug("add authorization header to the request")
-		req.Header.Add("Authorization", bearer)
-
-		log.Debug("make request... get back a response")
-		res, err := http.DefaultClient.Do(req)
-		if err != nil {
-			log.Error("http.DefaultClient.Do returned an error:", err)
-		}
-		defer res.Body.Close()
-
-		if res.StatusCode != 200 {
-			log.Debug("response status code: ", res.StatusCode, " continuing to next plugin")
-			continue
-		}
-
-		log.Debug("get the body out of the response")
-		body, err := ioutil.ReadAll(res.Body)
-		if err != nil {
-			log.Error("ioutil.ReadAll returned an error:", err)
-		}
-
-		if len(body) < 1 {
-			log.Debug("body was empty, continue to next plugin")
-			continue
-		}
-
-		log.Debug("create temp map object")
-		var tempMap restPluginsAvailable
-
-		log.Debug("unmarshal (turn unicode back into a string) request body into map structure")
-		err = json.Unmarshal(body, &tempMap)
-		if err != nil {
-			log.Error("error Unmarshalling: ", err)
-			log.Info("Problem unmarshalling the following string: ", string(body))
-		}
-
-		// add the enabled value from the plugin map to the available map
-		tempMap.Enabled = plugin.Enabled
-
-		log.Debug("adding plugin: ", tempMap.Name, ", and Key: ", tempMap.Key)
-		availablePluginsMap = append(availablePluginsMap, tempMap)
-
-	}
-
-	return availablePluginsMap
-}
-
 // boolToFloat converts a boolean value to a float64
 func boolToFloat(b bool) float64 {
 	if b {
@@ -451,14 +588,17 @@ func main() {
 		usage()
 	}
 
-	// check for required arguments
-	if *fqdn == "" {
-		fmt.Printf("-app.fqdn must be provided\n\n")
-		usage()
-	}
-	if *token == "" {
-		fmt.Printf("-app.token must be provided\n\n")
-		usage()
+	// -app.fqdn/-app.token are only required for the single-target /metrics endpoint; a
+	// fleet-only deployment can rely entirely on -config.file and /probe.
+	if *configFile == "" {
+		if *fqdn == "" {
+			fmt.Printf("-app.fqdn must be provided (or pass -config.file for fleet-only /probe scraping)\n\n")
+			usage()
+		}
+		if *token == "" {
+			fmt.Printf("-app.token must be provided (or pass -config.file for fleet-only /probe scraping)\n\n")
+			usage()
+		}
 	}
 
 	// adjust the logrus logger if arguments passed
@@ -477,8 +617,25 @@ func main() {
 	}
 
 	// Create a new instance of the Collector and then register it with the prometheus client.
-	upmCollector := newAtlassianUPMCollector()
-	prometheus.MustRegister(upmCollector)
+	// Only done when -app.fqdn/-app.token were actually provided: a fleet-only deployment
+	// (-config.file with no single default target) has nothing to serve at /metrics.
+	if *fqdn != "" && *token != "" {
+		upmCollector := newAtlassianUPMCollector(*fqdn, *token, *protocol, *userInstalled, *dropDisabled, *dropJiraSoftware, *checkUpdates, *checkLicenses)
+		prometheus.MustRegister(upmCollector)
+		log.Debug("url: ", upmCollector.baseURL)
+	}
+
+	// -config.file is optional: when provided it enables /probe for scraping an entire
+	// fleet of targets from this one exporter instance, in addition to the single-target
+	// /metrics endpoint above.
+	if *configFile != "" {
+		log.Debug("loading -config.file: ", *configFile)
+		cfg, err := loadTargetsConfig(*configFile)
+		if err != nil {
+			log.Fatal("failed to load -config.file: ", err)
+		}
+		targetsCfg = cfg
+	}
 
 	log.Info("starting...")
 
@@ -493,12 +650,21 @@ func main() {
 	log.Debug("add /favicon.ico handler") // because browsers request /favicon.ico, we add a handler so our metrics don't get false calls
 	http.HandleFunc("/favicon.ico", faviconHandler)
 
-	log.Debug("add /metrics handler")
-	http.Handle("/metrics", promhttp.Handler())
+	if *fqdn != "" && *token != "" {
+		log.Debug("add /metrics handler")
+		http.Handle("/metrics", promhttp.Handler())
+	}
+
+	if *configFile != "" {
+		log.Debug("add /probe handler")
+		http.HandleFunc("/probe", probeHandler)
+	}
 
-	log.Debug("set rest plugins url from arguments")
-	baseURL = *protocol + "://" + *fqdn + "/rest/plugins/latest/"
-	log.Debug("url: ", baseURL)
+	if *enableActions {
+		log.Debug("add /plugins/ action handlers")
+		prometheus.MustRegister(pluginActionTotal)
+		http.HandleFunc("/plugins/", pluginActionHandler)
+	}
 
 	log.Debug("make a channel of type os.Signal with a 1 space buffer size")
 	ch := make(chan os.Signal, 1)
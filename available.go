@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// getAvailablePluginInfo fetches the UPM "available" info for every plugin in restPluginsMap.
+// On instances with 100+ plugins doing this serially can blow past Prometheus' scrape_timeout,
+// so the work is dispatched over a bounded worker pool sized by -check-updates-concurrency.
+// ctx is the scrape's context, cancelling it (e.g. the scrape timing out) stops in-flight and
+// queued requests. Any plugin that fails to fetch increments errCounter instead of being
+// silently skipped. baseURL and bearer are passed in rather than read from package globals so
+// that concurrent /probe requests for different targets don't race.
+func getAvailablePluginInfo(ctx context.Context, restPluginsMap restPlugins, baseURL, bearer string, errCounter *prometheus.CounterVec) []restPluginsAvailable {
+	concurrency := *checkUpdatesConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	jobs := make(chan restPlugin)
+	results := make(chan restPluginsAvailable)
+
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for plugin := range jobs {
+				available, ok := fetchAvailablePluginInfo(ctx, baseURL, bearer, plugin, errCounter)
+				if ok {
+					results <- available
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, plugin := range restPluginsMap.Plugins {
+			select {
+			case jobs <- plugin:
+			case <-ctx.Done():
+				log.Debug("context done, stopping dispatch of remaining available checks")
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var availablePluginsMap []restPluginsAvailable
+	for available := range results {
+		availablePluginsMap = append(availablePluginsMap, available)
+	}
+
+	// workers can finish in any order, sort by key so Collect's output is deterministic
+	// between scrapes.
+	sort.Slice(availablePluginsMap, func(i, j int) bool {
+		return availablePluginsMap[i].Key < availablePluginsMap[j].Key
+	})
+
+	return availablePluginsMap
+}
+
+// fetchAvailablePluginInfo fetches and parses the available/update info for a single plugin.
+// ok is false if the request, response or body could not be used, in which case errCounter has
+// already been incremented for plugin.Key and the caller should skip the result.
+func fetchAvailablePluginInfo(ctx context.Context, baseURL, bearer string, plugin restPlugin, errCounter *prometheus.CounterVec) (restPluginsAvailable, bool) {
+	var tempMap restPluginsAvailable
+
+	log.Debug("getting: ", plugin.Name, ", available info")
+	availablePluginURL := baseURL + "available/" + plugin.Key + "-key"
+	log.Debug("requesting URL: " + availablePluginURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", availablePluginURL, nil)
+	if err != nil {
+		log.Error("http.NewRequestWithContext returned an error:", err)
+		errCounter.WithLabelValues(plugin.Key).Inc()
+		return tempMap, false
+	}
+
+	log.Debug("add authorization header to the request")
+	req.Header.Add("Authorization", bearer)
+
+	log.Debug("make request... get back a response")
+	res, err := sharedHTTPClient.Do(req)
+	if err != nil {
+		log.Error("sharedHTTPClient.Do returned an error:", err)
+		errCounter.WithLabelValues(plugin.Key).Inc()
+		return tempMap, false
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		log.Debug("response status code: ", res.StatusCode, " for plugin: ", plugin.Key)
+		errCounter.WithLabelValues(plugin.Key).Inc()
+		return tempMap, false
+	}
+
+	log.Debug("get the body out of the response")
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Error("ioutil.ReadAll returned an error:", err)
+		errCounter.WithLabelValues(plugin.Key).Inc()
+		return tempMap, false
+	}
+
+	if len(body) < 1 {
+		log.Debug("body was empty for plugin: ", plugin.Key)
+		errCounter.WithLabelValues(plugin.Key).Inc()
+		return tempMap, false
+	}
+
+	log.Debug("unmarshal (turn unicode back into a string) request body into map structure")
+	if err := json.Unmarshal(body, &tempMap); err != nil {
+		log.Error("error Unmarshalling: ", err)
+		log.Info("Problem unmarshalling the following string: ", string(body))
+		errCounter.WithLabelValues(plugin.Key).Inc()
+		return tempMap, false
+	}
+
+	// add the enabled value from the plugin map to the available map
+	tempMap.Enabled = plugin.Enabled
+
+	log.Debug("adding plugin: ", tempMap.Name, ", and Key: ", tempMap.Key)
+	return tempMap, true
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io/ioutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+// targetConfig holds the per-target credentials and scrape options resolved from the
+// -config.file YAML document, keyed by the target's FQDN.
+type targetConfig struct {
+	Token            string `yaml:"token"`
+	Protocol         string `yaml:"protocol"`
+	UserInstalled    bool   `yaml:"userInstalled"`
+	DropDisabled     bool   `yaml:"dropDisabled"`
+	DropJiraSoftware bool   `yaml:"dropJiraSoftware"`
+	CheckUpdates     bool   `yaml:"checkUpdates"`
+	CheckLicenses    bool   `yaml:"checkLicenses"`
+}
+
+// targetsConfig is the root structure of the -config.file YAML document, e.g.:
+//
+//	targets:
+//	  bitbucket.example.com:
+//	    token: "base64-basic-auth-token"
+//	    protocol: https
+//	    dropDisabled: true
+//	  jira.example.com:
+//	    token: "base64-basic-auth-token"
+//	    dropJiraSoftware: true
+//	    checkUpdates: true
+//	    checkLicenses: true
+type targetsConfig struct {
+	Targets map[string]targetConfig `yaml:"targets"`
+}
+
+// loadTargetsConfig reads and parses the YAML file at path, returning the per-FQDN target
+// configuration used by the /probe handler to scrape an Atlassian fleet.
+func loadTargetsConfig(path string) (targetsConfig, error) {
+	var cfg targetsConfig
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
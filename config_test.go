@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadTargetsConfig(t *testing.T) {
+	t.Run("parses a well-formed targets file", func(t *testing.T) {
+		path := writeTempConfig(t, `
+targets:
+  bitbucket.example.com:
+    token: "bitbucket-token"
+    protocol: https
+    dropDisabled: true
+  jira.example.com:
+    token: "jira-token"
+    dropJiraSoftware: true
+    checkUpdates: true
+`)
+
+		cfg, err := loadTargetsConfig(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(cfg.Targets) != 2 {
+			t.Fatalf("len(Targets) = %d, want 2", len(cfg.Targets))
+		}
+
+		bitbucket, ok := cfg.Targets["bitbucket.example.com"]
+		if !ok {
+			t.Fatalf("missing bitbucket.example.com target")
+		}
+		if bitbucket.Token != "bitbucket-token" || bitbucket.Protocol != "https" || !bitbucket.DropDisabled {
+			t.Errorf("bitbucket.example.com = %+v, unexpected values", bitbucket)
+		}
+
+		jira, ok := cfg.Targets["jira.example.com"]
+		if !ok {
+			t.Fatalf("missing jira.example.com target")
+		}
+		if jira.Token != "jira-token" || !jira.DropJiraSoftware || !jira.CheckUpdates {
+			t.Errorf("jira.example.com = %+v, unexpected values", jira)
+		}
+	})
+
+	t.Run("missing file returns an error", func(t *testing.T) {
+		if _, err := loadTargetsConfig(filepath.Join(t.TempDir(), "does-not-exist.yaml")); err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+	})
+
+	t.Run("malformed YAML returns an error", func(t *testing.T) {
+		path := writeTempConfig(t, "targets: [this is not a map")
+
+		if _, err := loadTargetsConfig(path); err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+	})
+}
+
+// writeTempConfig writes contents to a temp file and returns its path.
+func writeTempConfig(t *testing.T, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "targets.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("failed to write temp config: %v", err)
+	}
+
+	return path
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var checkLicenses = flag.Bool("check-licenses", false, "fetch and export license expiry/maintenance info for plugins that use licensing (1 extra connection per licensed plugin)")
+
+// restPluginLicense is associated with the UPM /rest/plugins/1.0/{key}-key/license JSON
+// structure. ExpiryDate/MaintenanceExpiryDate are epoch milliseconds, 0 means "not set"
+// (e.g. a perpetual/non-expiring license).
+type restPluginLicense struct {
+	Valid                 bool  `json:"valid"`
+	ExpiryDate            int64 `json:"expiryDate"`
+	MaintenanceExpiryDate int64 `json:"maintenanceExpiryDate"`
+}
+
+// fetchPluginLicense fetches and parses the license info for the plugin identified by key.
+func fetchPluginLicense(ctx context.Context, baseURL, bearer, key string) (restPluginLicense, error) {
+	var license restPluginLicense
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+key+"-key/license", nil)
+	if err != nil {
+		return license, err
+	}
+	req.Header.Add("Authorization", bearer)
+
+	res, err := sharedHTTPClient.Do(req)
+	if err != nil {
+		return license, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		// A non-200 here (transient 5xx, an endpoint this UPM version doesn't implement, etc.)
+		// means we don't actually know the license state. Returning the zero-value license with
+		// a nil error would publish it as "invalid, expired at epoch" and fire false license
+		// alerts, so treat it as an error and let the caller skip the metric instead.
+		return license, fmt.Errorf("UPM returned status %d fetching license for plugin: %s", res.StatusCode, key)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return license, err
+	}
+
+	if err := json.Unmarshal(body, &license); err != nil {
+		log.Error("error Unmarshalling license for plugin: ", key, ", error: ", err)
+		return license, err
+	}
+
+	return license, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// probeHandler accepts calls to /probe?target=<fqdn>&app=<bitbucket|confluence|jira>, the same
+// pattern blackbox_exporter uses for its own /probe endpoint. target is resolved against the
+// targets loaded from -config.file to find its token/protocol/flags, a dedicated collector and
+// registry are built for just that one target, and the result is served in place so the existing
+// /metrics endpoint keeps working unchanged for single-target deployments. app is accepted for
+// operator-facing clarity in logs/dashboards, it does not change how the target is scraped.
+func probeHandler(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "target parameter is required", http.StatusBadRequest)
+		return
+	}
+	app := r.URL.Query().Get("app")
+
+	cfg, ok := targetsCfg.Targets[target]
+	if !ok {
+		http.Error(w, "no -config.file entry found for target: "+target, http.StatusNotFound)
+		return
+	}
+
+	protocol := cfg.Protocol
+	if protocol == "" {
+		protocol = "https"
+	}
+
+	log.Debug("probe requested for target: ", target, ", app: ", app)
+
+	probeCollector := newAtlassianUPMCollector(target, cfg.Token, protocol, cfg.UserInstalled, cfg.DropDisabled, cfg.DropJiraSoftware, cfg.CheckUpdates, cfg.CheckLicenses)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(probeCollector)
+
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PluginStatus is the lifecycle state of a UPM plugin. The main /rest/plugins/1.0/ listing only
+// exposes an enabled/disabled/unloadable boolean trio; the richer transitional states below are
+// derived from /rest/plugins/1.0/pending/, which reports plugins with an install/upgrade task
+// currently in flight.
+type PluginStatus string
+
+// allPluginStatuses is every state atlassian_upm_plugin_status emits a series for, so the metric
+// always has one time series per {key,state} pair regardless of which state is current.
+var allPluginStatuses = []PluginStatus{
+	StatusEnabled,
+	StatusDisabled,
+	StatusUnloadable,
+	StatusFailedToStart,
+	StatusInstalling,
+	StatusUpgrading,
+	StatusAwaitingRestart,
+}
+
+const (
+	StatusEnabled         PluginStatus = "enabled"
+	StatusDisabled        PluginStatus = "disabled"
+	StatusUnloadable      PluginStatus = "unloadable"
+	StatusFailedToStart   PluginStatus = "failed-to-start"
+	StatusInstalling      PluginStatus = "installing"
+	StatusUpgrading       PluginStatus = "upgrading"
+	StatusAwaitingRestart PluginStatus = "awaiting-restart"
+)
+
+// statusHistoryLimit bounds how many past observations are kept per plugin key.
+const statusHistoryLimit = 10
+
+// statusObservation is a single recorded status for a plugin at a point in time.
+type statusObservation struct {
+	Status    PluginStatus
+	Timestamp time.Time
+}
+
+// statusStore remembers the last statusHistoryLimit observed states per plugin key, so Collect
+// can detect a transition between this scrape and the last one.
+type statusStore struct {
+	mu      sync.Mutex
+	history map[string][]statusObservation
+}
+
+// newStatusStore returns an empty statusStore.
+func newStatusStore() *statusStore {
+	return &statusStore{history: make(map[string][]statusObservation)}
+}
+
+// observe records status for key at now, returning the previously observed status (if any) so
+// the caller can tell whether this is a transition.
+func (s *statusStore) observe(key string, status PluginStatus, now time.Time) (previous PluginStatus, hadPrevious bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hist := s.history[key]
+	if len(hist) > 0 {
+		previous = hist[len(hist)-1].Status
+		hadPrevious = true
+	}
+
+	hist = append(hist, statusObservation{Status: status, Timestamp: now})
+	if len(hist) > statusHistoryLimit {
+		hist = hist[len(hist)-statusHistoryLimit:]
+	}
+	s.history[key] = hist
+
+	return previous, hadPrevious
+}
+
+// restPendingTasks is associated with the UPM /rest/plugins/1.0/pending/ JSON structure, which
+// lists plugins that currently have an install/upgrade task in flight and require a restart.
+type restPendingTasks struct {
+	Pending []struct {
+		Key  string `json:"key"`
+		Type string `json:"type"` // e.g. "install" or "upgrade"
+	} `json:"pending"`
+}
+
+// pluginStatusFor derives a PluginStatus for plugin, given the pending task type (if any)
+// reported for its key by fetchPendingTasks. A plugin UPM marks unloadable because it threw
+// while starting up (UnloadableText set) is reported as failed-to-start rather than the more
+// generic unloadable, which is reserved for plugins that are simply not applicable/incompatible.
+func pluginStatusFor(plugin restPlugin, pendingType string, hadPending bool) PluginStatus {
+	if hadPending {
+		switch pendingType {
+		case "upgrade":
+			return StatusUpgrading
+		case "restart":
+			return StatusAwaitingRestart
+		default:
+			return StatusInstalling
+		}
+	}
+
+	if plugin.Unloadable {
+		if plugin.UnloadableText != "" {
+			return StatusFailedToStart
+		}
+		return StatusUnloadable
+	}
+
+	if plugin.Enabled {
+		return StatusEnabled
+	}
+
+	return StatusDisabled
+}
+
+// fetchPendingTasks polls baseURL+"pending/" and returns a map of plugin key to pending task
+// type, used both to derive installing/upgrading PluginStatus values and to populate
+// atlassian_upm_pending_tasks.
+func fetchPendingTasks(ctx context.Context, baseURL, bearer string) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"pending/", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", bearer)
+
+	res, err := sharedHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		log.Debug("response status code: ", res.StatusCode, " fetching pending tasks")
+		return map[string]string{}, nil
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending restPendingTasks
+	if err := json.Unmarshal(body, &pending); err != nil {
+		log.Error("error Unmarshalling pending tasks: ", err)
+		return nil, err
+	}
+
+	pendingByKey := make(map[string]string, len(pending.Pending))
+	for _, task := range pending.Pending {
+		pendingByKey[task.Key] = task.Type
+	}
+
+	return pendingByKey, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPluginStatusFor(t *testing.T) {
+	tests := []struct {
+		name        string
+		plugin      restPlugin
+		pendingType string
+		hadPending  bool
+		want        PluginStatus
+	}{
+		{
+			name:       "enabled",
+			plugin:     restPlugin{Enabled: true},
+			hadPending: false,
+			want:       StatusEnabled,
+		},
+		{
+			name:       "disabled",
+			plugin:     restPlugin{Enabled: false},
+			hadPending: false,
+			want:       StatusDisabled,
+		},
+		{
+			name:       "unloadable without reason",
+			plugin:     restPlugin{Unloadable: true},
+			hadPending: false,
+			want:       StatusUnloadable,
+		},
+		{
+			name:       "unloadable with reason is failed-to-start",
+			plugin:     restPlugin{Unloadable: true, UnloadableText: "NoClassDefFoundError"},
+			hadPending: false,
+			want:       StatusFailedToStart,
+		},
+		{
+			name:        "pending install",
+			plugin:      restPlugin{},
+			pendingType: "install",
+			hadPending:  true,
+			want:        StatusInstalling,
+		},
+		{
+			name:        "pending upgrade",
+			plugin:      restPlugin{},
+			pendingType: "upgrade",
+			hadPending:  true,
+			want:        StatusUpgrading,
+		},
+		{
+			name:        "pending restart",
+			plugin:      restPlugin{},
+			pendingType: "restart",
+			hadPending:  true,
+			want:        StatusAwaitingRestart,
+		},
+		{
+			name:        "pending takes priority over unloadable",
+			plugin:      restPlugin{Unloadable: true, UnloadableText: "boom"},
+			pendingType: "upgrade",
+			hadPending:  true,
+			want:        StatusUpgrading,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pluginStatusFor(tt.plugin, tt.pendingType, tt.hadPending)
+			if got != tt.want {
+				t.Errorf("pluginStatusFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusStoreObserve(t *testing.T) {
+	now := time.Now()
+
+	t.Run("first observation has no previous", func(t *testing.T) {
+		s := newStatusStore()
+
+		previous, hadPrevious := s.observe("plugin-a", StatusEnabled, now)
+		if hadPrevious {
+			t.Errorf("hadPrevious = true on first observation, want false")
+		}
+		if previous != "" {
+			t.Errorf("previous = %q on first observation, want empty", previous)
+		}
+	})
+
+	t.Run("second observation reports the prior status", func(t *testing.T) {
+		s := newStatusStore()
+
+		s.observe("plugin-a", StatusEnabled, now)
+		previous, hadPrevious := s.observe("plugin-a", StatusDisabled, now.Add(time.Minute))
+		if !hadPrevious {
+			t.Fatalf("hadPrevious = false on second observation, want true")
+		}
+		if previous != StatusEnabled {
+			t.Errorf("previous = %v, want %v", previous, StatusEnabled)
+		}
+	})
+
+	t.Run("keys are tracked independently", func(t *testing.T) {
+		s := newStatusStore()
+
+		s.observe("plugin-a", StatusEnabled, now)
+		_, hadPrevious := s.observe("plugin-b", StatusDisabled, now)
+		if hadPrevious {
+			t.Errorf("hadPrevious = true for a key observed for the first time, want false")
+		}
+	})
+
+	t.Run("history is bounded to statusHistoryLimit", func(t *testing.T) {
+		s := newStatusStore()
+
+		for i := 0; i < statusHistoryLimit+5; i++ {
+			s.observe("plugin-a", StatusEnabled, now.Add(time.Duration(i)*time.Minute))
+		}
+		if got := len(s.history["plugin-a"]); got != statusHistoryLimit {
+			t.Errorf("len(history) = %d, want %d", got, statusHistoryLimit)
+		}
+	})
+}
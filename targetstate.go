@@ -0,0 +1,73 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// targetState bundles the collector state that must persist across scrapes of the same target
+// FQDN even though /probe builds a brand new *atlassianUPMCollector per request. Without this,
+// statusStore history would reset on every probe (so a transition could never be detected) and
+// the _total counters would reset to 0 on every probe (breaking rate()/increase() for them).
+type targetState struct {
+	statuses             *statusStore
+	stateTransitions     *prometheus.CounterVec
+	licenseCheckErrors   *prometheus.CounterVec
+	availableCheckErrors *prometheus.CounterVec
+}
+
+var (
+	targetStatesMu sync.Mutex
+	targetStates   = map[string]*targetState{}
+)
+
+// targetStateFor returns the persistent state for fqdn, creating it on first use. Safe for
+// concurrent use by overlapping /probe requests for different (or the same) target.
+func targetStateFor(fqdn string) *targetState {
+	targetStatesMu.Lock()
+	defer targetStatesMu.Unlock()
+
+	if state, ok := targetStates[fqdn]; ok {
+		return state
+	}
+
+	state := &targetState{
+		statuses: newStatusStore(),
+		stateTransitions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: metricNamespace,
+				Name:      "plugin_state_transitions_total",
+				Help:      "Counts plugin state transitions observed between scrapes",
+			},
+			[]string{
+				"key",
+				"from",
+				"to",
+			},
+		),
+		licenseCheckErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: metricNamespace,
+				Name:      "license_check_errors_total",
+				Help:      "Counts errors encountered while checking a plugin's license via the UPM license endpoint",
+			},
+			[]string{
+				"plugin",
+			},
+		),
+		availableCheckErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: metricNamespace,
+				Name:      "available_check_errors_total",
+				Help:      "Counts errors encountered while checking a plugin's available/update info via the UPM available endpoint",
+			},
+			[]string{
+				"plugin",
+			},
+		),
+	}
+	targetStates[fqdn] = state
+
+	return state
+}